@@ -21,6 +21,12 @@ func NewCommandPolicyRules() *cobra.Command {
 	regex := ""
 	inverse := false
 	output := "table"
+	minimize := false
+	query := ""
+	celExpr := ""
+	risk := false
+	riskPolicyPath := ""
+	minSeverity := ""
 	// Support overrides
 	cmd := &cobra.Command{
 		Use:     "policy-rules",
@@ -43,6 +49,12 @@ rbac-tool policy-rules -e '^system:.*'
 # Lookup all accounts that DO NOT start with system: )
 rbac-tool policy-rules -ne '^system:.*'
 
+# Filter subjects using a JMESPath expression (requires -o json|yaml)
+rbac-tool policy-rules -o json --query "[?contains(allowedTo[].verbs[], 'get')]"
+
+# Filter subjects using a CEL expression
+rbac-tool policy-rules --cel "rules.exists(r, 'secrets' in r.resources && '*' in r.verbs)"
+
 `,
 		Hidden: false,
 		RunE: func(c *cobra.Command, args []string) error {
@@ -97,6 +109,69 @@ rbac-tool policy-rules -ne '^system:.*'
 				filteredPolicies = append(filteredPolicies, policy)
 			}
 
+			if celExpr != "" {
+				filteredPolicies, err = rbac.FilterByCEL(filteredPolicies, celExpr)
+				if err != nil {
+					return err
+				}
+			}
+
+			if query != "" {
+				if output != "json" && output != "yaml" {
+					return fmt.Errorf("--query requires -o json or -o yaml")
+				}
+
+				result, err := rbac.FilterByJMESPath(rbac.NewSubjectPermissionsList(filteredPolicies), query)
+				if err != nil {
+					return err
+				}
+
+				if output == "yaml" {
+					data, err := yaml.Marshal(result)
+					if err != nil {
+						return fmt.Errorf("Processing error - %v", err)
+					}
+					fmt.Println(string(data))
+					return nil
+				}
+
+				data, err := json.Marshal(result)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if minimize {
+				sourced, err := rbac.CollectSubjectRules(client)
+				if err != nil {
+					return err
+				}
+
+				return renderMinimized(rbac.MinimizeSubjectPermissions(filteredPolicies, sourced), output)
+			}
+
+			if risk || output == "risk" {
+				riskPolicy := rbac.DefaultRiskPolicy()
+				if riskPolicyPath != "" {
+					riskPolicy, err = rbac.LoadRiskPolicy(riskPolicyPath)
+					if err != nil {
+						return err
+					}
+				}
+
+				severity := rbac.Severity("")
+				if minSeverity != "" {
+					severity, err = rbac.ParseSeverity(minSeverity)
+					if err != nil {
+						return err
+					}
+				}
+
+				return renderRiskScores(rbac.ScoreSubjects(riskPolicy, filteredPolicies), output, severity)
+			}
+
 			rows := [][]string{}
 
 			for _, p := range filteredPolicies {
@@ -205,16 +280,171 @@ rbac-tool policy-rules -ne '^system:.*'
 		},
 	}
 
-	/**
-	jmespath
-	[? contains(@.allowedTo[].verbs[], 'get')] | [? contains(@.allowedTo[].apiGroups[], 'core')]
-	*/
-
 	flags := cmd.Flags()
 	flags.StringVar(&clusterContext, "cluster-context", "", "Cluster Context .use 'kubectl config get-contexts' to list available contexts")
-	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml")
+	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml | risk")
 
 	flags.StringVarP(&regex, "regex", "e", "", "Specify whether run the lookup using a regex match")
 	flags.BoolVarP(&inverse, "not", "n", false, "Inverse the regex matching. Use to search for users that do not match '^system:.*'")
+	flags.BoolVar(&minimize, "minimize", false, "Merge/dedupe overlapping rules per subject+namespace using RBAC covering semantics")
+	flags.BoolVar(&minimize, "dedupe", false, "Alias for --minimize")
+	flags.StringVar(&query, "query", "", "JMESPath expression evaluated against the SubjectPermissionsList JSON (requires -o json or -o yaml)")
+	flags.StringVar(&celExpr, "cel", "", "CEL boolean expression evaluated per subject, with 'subject' and 'rules' in scope, to filter the result")
+	flags.BoolVar(&risk, "risk", false, "Annotate each subject with a risk score based on well-known dangerous permission patterns")
+	flags.StringVar(&riskPolicyPath, "risk-policy", "", "Path to a YAML risk policy file (defaults to the built-in policy)")
+	flags.StringVar(&minSeverity, "min-severity", "", "Only show subjects at or above this severity (Low|Medium|High|Critical); exits non-zero if any match")
 	return cmd
 }
+
+// renderMinimized prints the --minimize result, including the RuleSource
+// provenance of every surviving rule in json/yaml output.
+func renderMinimized(policies []rbac.MinimizedSubjectPermissions, output string) error {
+	switch output {
+	case "yaml":
+		data, err := yaml.Marshal(&policies)
+		if err != nil {
+			return fmt.Errorf("Processing error - %v", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	case "json":
+		data, err := json.Marshal(&policies)
+		if err != nil {
+			return fmt.Errorf("Processing error - %v", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	case "table":
+		rows := [][]string{}
+		for _, p := range policies {
+			for namespace, rules := range p.Rules {
+				if namespace == "" {
+					namespace = "*"
+				}
+
+				for _, rule := range rules {
+					sources := []string{}
+					for _, s := range rule.Sources {
+						sources = append(sources, fmt.Sprintf("%v/%v via %v", s.RoleKind, s.RoleName, s.BindingName))
+					}
+
+					rows = append(rows, []string{
+						p.Subject.Kind,
+						p.Subject.Name,
+						strings.Join(rule.Verbs, ","),
+						namespace,
+						strings.Join(rule.APIGroups, ","),
+						strings.Join(rule.Resources, ","),
+						strings.Join(rule.ResourceNames, ","),
+						strings.Join(sources, "; "),
+					})
+				}
+			}
+		}
+
+		sort.Slice(rows, func(i, j int) bool {
+			if strings.Compare(rows[i][0], rows[j][0]) == 0 {
+				return strings.Compare(rows[i][1], rows[j][1]) < 0
+			}
+
+			return strings.Compare(rows[i][0], rows[j][0]) < 0
+		})
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"TYPE", "SUBJECT", "VERBS", "NAMESPACE", "API GROUP", "RESOURCE", "NAMES", "SOURCES"})
+		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+		table.SetBorder(false)
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.AppendBulk(rows)
+		table.Render()
+
+		return nil
+	default:
+		return fmt.Errorf("Unsupported output format")
+	}
+}
+
+// renderRiskScores prints subject risk scores in the requested format,
+// filtered to severity >= minSeverity when set. It returns a non-nil error
+// (causing a non-zero exit code) if minSeverity is set and at least one
+// subject is at or above it, so this can plug into CI.
+func renderRiskScores(scores []rbac.SubjectRiskScore, output string, minSeverity rbac.Severity) error {
+	if minSeverity != "" {
+		filtered := scores[:0]
+		for _, s := range scores {
+			if rbac.RuleAtLeastAsSevereAs(s.Severity, minSeverity) {
+				filtered = append(filtered, s)
+			}
+		}
+		scores = filtered
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+
+	switch output {
+	case "yaml":
+		data, err := yaml.Marshal(&scores)
+		if err != nil {
+			return fmt.Errorf("Processing error - %v", err)
+		}
+		fmt.Println(string(data))
+	case "json":
+		data, err := json.Marshal(&scores)
+		if err != nil {
+			return fmt.Errorf("Processing error - %v", err)
+		}
+		fmt.Println(string(data))
+	default:
+		rows := [][]string{}
+		for _, s := range scores {
+			rationales := []string{}
+			for _, f := range s.Findings {
+				rationales = append(rationales, fmt.Sprintf("%v: %v", f.Rule, f.Rationale))
+			}
+
+			rows = append(rows, []string{
+				s.Subject.Kind,
+				s.Subject.Name,
+				fmt.Sprintf("%v", s.Score),
+				colorizeSeverity(s.Severity),
+				strings.Join(rationales, "; "),
+			})
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"TYPE", "SUBJECT", "SCORE", "SEVERITY", "FINDINGS"})
+		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+		table.SetBorder(false)
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.AppendBulk(rows)
+		table.Render()
+	}
+
+	if minSeverity != "" && len(scores) > 0 {
+		return fmt.Errorf("%d subject(s) at or above severity %q", len(scores), minSeverity)
+	}
+
+	return nil
+}
+
+// colorizeSeverity wraps a severity label in an ANSI color code for the
+// table renderer, so dangerous subjects stand out at a glance.
+func colorizeSeverity(severity rbac.Severity) string {
+	colorCode := ""
+	switch severity {
+	case rbac.SeverityCritical:
+		colorCode = "31" // red
+	case rbac.SeverityHigh:
+		colorCode = "33" // yellow
+	case rbac.SeverityMedium:
+		colorCode = "36" // cyan
+	case rbac.SeverityLow:
+		colorCode = "32" // green
+	default:
+		return string(severity)
+	}
+
+	return fmt.Sprintf("\x1b[%vm%v\x1b[0m", colorCode, severity)
+}
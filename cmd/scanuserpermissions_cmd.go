@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/alcideio/rbac-tool/pkg/kube"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// NewCommandScanUserPermissions creates the `scan-user-permissions` command
+// which flags RoleBinding/ClusterRoleBinding subjects that are orphaned,
+// reference an external identity, or dangle off a missing Role/ClusterRole.
+func NewCommandScanUserPermissions() *cobra.Command {
+
+	clusterContext := ""
+	output := "table"
+	externalIdentityRegexes := rbac.ExternalIdentityRegexes
+	flagSAImpersonation := false
+
+	cmd := &cobra.Command{
+		Use:     "scan-user-permissions",
+		Aliases: []string{"scan-permissions", "sup"},
+		Short:   "Scan RoleBindings/ClusterRoleBindings for orphaned, external, or dangling subjects",
+		Long: `
+Walk all RoleBindings and ClusterRoleBindings in the cluster and report
+subjects that:
+
+  - reference ServiceAccounts whose namespace or SA object no longer exists
+  - reference Users/Groups matching a configurable external-identity regex
+    (e.g. cloud IAM UIDs, deleted LDAP users)
+  - bind to non-existent Roles/ClusterRoles (dangling roleRefs)
+
+A User/Group subject literally named "system:serviceaccount:<ns>:<name>" is
+a legitimate impersonation/identity-federation pattern, not flagged by
+default - pass --flag-sa-impersonation-pattern to opt in to treating it as
+suspicious.
+
+Examples:
+
+# Scan with the default external-identity patterns
+rbac-tool scan-user-permissions
+
+# Scan using custom external-identity patterns
+rbac-tool scan-user-permissions --external-identity-regex '^CN=.*,OU=ext,.*$'
+`,
+		Hidden: false,
+		RunE: func(c *cobra.Command, args []string) error {
+			client, err := kube.NewClient(clusterContext)
+			if err != nil {
+				return fmt.Errorf("Failed to create kubernetes client - %v", err)
+			}
+
+			regexes := externalIdentityRegexes
+			if flagSAImpersonation {
+				regexes = append(append([]string{}, regexes...), rbac.SAAsUserImpersonationRegex)
+			}
+
+			findings, err := rbac.ScanOrphanedSubjects(client, regexes)
+			if err != nil {
+				return err
+			}
+
+			return renderOrphanFindings(findings, output)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&clusterContext, "cluster-context", "", "Cluster Context .use 'kubectl config get-contexts' to list available contexts")
+	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml")
+	flags.StringArrayVar(&externalIdentityRegexes, "external-identity-regex", externalIdentityRegexes, "Regex matching an external identity Subject.Name - may be specified multiple times")
+	flags.BoolVar(&flagSAImpersonation, "flag-sa-impersonation-pattern", false, "Also flag User/Group subjects named like a ServiceAccount (system:serviceaccount:<ns>:<name>) - opt-in, since this is also a legitimate impersonation pattern")
+
+	return cmd
+}
+
+// NewCommandCleanupUserPermissions creates the `cleanup-user-permissions`
+// command which emits (and, with --apply, runs) the kubectl delete
+// operations needed to remove bindings flagged by scan-user-permissions.
+func NewCommandCleanupUserPermissions() *cobra.Command {
+
+	clusterContext := ""
+	output := "table"
+	externalIdentityRegexes := rbac.ExternalIdentityRegexes
+	flagSAImpersonation := false
+	subjectUID := ""
+	apply := false
+	confirmApplyAll := false
+
+	cmd := &cobra.Command{
+		Use:     "cleanup-user-permissions",
+		Aliases: []string{"cleanup-permissions", "cup"},
+		Short:   "Remove RoleBinding/ClusterRoleBinding subjects flagged by scan-user-permissions",
+		Long: `
+Emit the kubectl delete rolebinding/clusterrolebinding commands required to
+remove bindings flagged by 'scan-user-permissions'. Defaults to a dry-run;
+pass --apply to actually delete.
+
+WARNING: this deletes the entire flagged binding, not just the flagged
+subject. If a binding lists other, legitimate subjects alongside the
+flagged one, deleting it revokes their access too - a binding flagged as
+having more than one subject prints a warning before it is deleted.
+Review the dry-run output and the binding's subjects before using --apply.
+
+Applying to every flagged binding in the cluster (--apply with no
+--subject-uid) is destructive, so it additionally requires
+--confirm-apply-all as a safety net against accidentally deleting bindings
+that scan-user-permissions flagged as a false positive.
+
+Examples:
+
+# Dry-run cleanup for a specific flagged subject
+rbac-tool cleanup-user-permissions --subject-uid='ServiceAccount/kube-system/default'
+
+# Apply the cleanup for just that flagged subject
+rbac-tool cleanup-user-permissions --subject-uid='ServiceAccount/kube-system/default' --apply
+
+# Apply the cleanup for every flagged subject in the cluster
+rbac-tool cleanup-user-permissions --apply --confirm-apply-all
+`,
+		Hidden: false,
+		RunE: func(c *cobra.Command, args []string) error {
+			if apply && subjectUID == "" && !confirmApplyAll {
+				return fmt.Errorf("--apply with no --subject-uid would delete every flagged binding in the cluster - pass --confirm-apply-all to proceed, or scope the cleanup with --subject-uid")
+			}
+
+			client, err := kube.NewClient(clusterContext)
+			if err != nil {
+				return fmt.Errorf("Failed to create kubernetes client - %v", err)
+			}
+
+			regexes := externalIdentityRegexes
+			if flagSAImpersonation {
+				regexes = append(append([]string{}, regexes...), rbac.SAAsUserImpersonationRegex)
+			}
+
+			findings, err := rbac.ScanOrphanedSubjects(client, regexes)
+			if err != nil {
+				return err
+			}
+
+			if subjectUID != "" {
+				filtered := findings[:0]
+				for _, f := range findings {
+					if f.SubjectUID() == subjectUID {
+						filtered = append(filtered, f)
+					}
+				}
+				findings = filtered
+			}
+
+			seen := map[string]bool{}
+			for _, f := range findings {
+				key := f.BindingKind + "/" + f.Namespace + "/" + f.BindingName
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				cmdLine := deleteCommandFor(f)
+
+				subjectCount, err := bindingSubjectCount(client, f)
+				if err != nil {
+					return err
+				}
+				if subjectCount > 1 {
+					fmt.Printf("# WARNING: %s %s has %d subjects - this deletes the whole binding, revoking access for all of them, not just %s/%s\n",
+						f.BindingKind, f.BindingName, subjectCount, f.Subject.Kind, f.Subject.Name)
+				}
+
+				if !apply {
+					fmt.Println("# dry-run (use --apply to execute):")
+					fmt.Println(cmdLine)
+					continue
+				}
+
+				fmt.Println(cmdLine)
+				if err := runKubectl(cmdLine); err != nil {
+					return fmt.Errorf("Failed to run %q - %v", cmdLine, err)
+				}
+			}
+
+			return renderOrphanFindings(findings, output)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&clusterContext, "cluster-context", "", "Cluster Context .use 'kubectl config get-contexts' to list available contexts")
+	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml")
+	flags.StringArrayVar(&externalIdentityRegexes, "external-identity-regex", externalIdentityRegexes, "Regex matching an external identity Subject.Name - may be specified multiple times")
+	flags.BoolVar(&flagSAImpersonation, "flag-sa-impersonation-pattern", false, "Also flag User/Group subjects named like a ServiceAccount (system:serviceaccount:<ns>:<name>) - opt-in, since this is also a legitimate impersonation pattern")
+	flags.StringVar(&subjectUID, "subject-uid", "", "Limit cleanup to the binding(s) containing this subject uid (Kind/Namespace/Name, see scan-user-permissions output)")
+	flags.BoolVar(&apply, "apply", false, "Actually delete the flagged bindings instead of printing a dry-run")
+	flags.BoolVar(&confirmApplyAll, "confirm-apply-all", false, "Required alongside --apply when --subject-uid is not set, to confirm deleting every flagged binding in the cluster")
+
+	return cmd
+}
+
+func runKubectl(cmdLine string) error {
+	args := strings.Fields(cmdLine)
+	if len(args) == 0 {
+		return fmt.Errorf("empty command")
+	}
+
+	kubectl := exec.Command(args[0], args[1:]...)
+	kubectl.Stdout = os.Stdout
+	kubectl.Stderr = os.Stderr
+	return kubectl.Run()
+}
+
+// bindingSubjectCount returns how many subjects the flagged binding
+// currently has, so the cleanup loop can warn before deleting a binding
+// that also grants access to other, non-flagged subjects.
+func bindingSubjectCount(client *kube.Client, f rbac.OrphanedBinding) (int, error) {
+	if f.BindingKind == "ClusterRoleBinding" {
+		crb, err := client.GetClientset().RbacV1().ClusterRoleBindings().Get(f.BindingName, metav1.GetOptions{})
+		if err != nil {
+			return 0, fmt.Errorf("Failed to get clusterrolebinding %s - %v", f.BindingName, err)
+		}
+		return len(crb.Subjects), nil
+	}
+
+	rb, err := client.GetClientset().RbacV1().RoleBindings(f.Namespace).Get(f.BindingName, metav1.GetOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get rolebinding %s/%s - %v", f.Namespace, f.BindingName, err)
+	}
+	return len(rb.Subjects), nil
+}
+
+func deleteCommandFor(f rbac.OrphanedBinding) string {
+	if f.BindingKind == "ClusterRoleBinding" {
+		return fmt.Sprintf("kubectl delete clusterrolebinding %s", f.BindingName)
+	}
+
+	return fmt.Sprintf("kubectl delete rolebinding %s -n %s", f.BindingName, f.Namespace)
+}
+
+func renderOrphanFindings(findings []rbac.OrphanedBinding, output string) error {
+	switch output {
+	case "table":
+		rows := [][]string{}
+		for _, f := range findings {
+			rows = append(rows, []string{
+				f.BindingKind,
+				f.BindingName,
+				f.Namespace,
+				string(f.Reason),
+				f.Subject.Kind,
+				f.Subject.Name,
+				f.Detail,
+			})
+		}
+
+		sort.Slice(rows, func(i, j int) bool {
+			return strings.Compare(rows[i][1], rows[j][1]) < 0
+		})
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"BINDING KIND", "BINDING", "NAMESPACE", "REASON", "SUBJECT KIND", "SUBJECT", "DETAIL"})
+		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+		table.SetBorder(false)
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.AppendBulk(rows)
+		table.Render()
+
+		return nil
+	case "yaml":
+		data, err := yaml.Marshal(&findings)
+		if err != nil {
+			return fmt.Errorf("Processing error - %v", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	case "json":
+		data, err := json.Marshal(&findings)
+		if err != nil {
+			return fmt.Errorf("Processing error - %v", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	default:
+		return fmt.Errorf("Unsupported output format")
+	}
+}
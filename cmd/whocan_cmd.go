@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/alcideio/rbac-tool/pkg/kube"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// NewCommandWhoCan creates the `who-can` command which answers two
+// complementary "effective access" questions against the aggregated
+// rbac.SubjectPermissions set:
+//
+//   - who can VERB RESOURCE [in NAMESPACE]?  (the default, kubectl auth
+//     reconcile-style question)
+//   - what can SUBJECT do?  (via --subject, mirrors `kubectl auth can-i
+//     --list --as SUBJECT`)
+func NewCommandWhoCan() *cobra.Command {
+
+	clusterContext := ""
+	output := "table"
+
+	verb := ""
+	resource := ""
+	subresource := ""
+	apiGroup := ""
+	resourceName := ""
+	namespace := ""
+	nonResourceURL := ""
+	subject := ""
+
+	cmd := &cobra.Command{
+		Use:     "who-can",
+		Aliases: []string{"whocan", "can-i"},
+		Short:   "Shows who has RBAC permissions to perform an action, or what actions a subject can perform",
+		Long: `
+Answer Kubernetes RBAC "effective access" questions against the set of
+RoleBindings/ClusterRoleBindings in the cluster.
+
+Examples:
+
+# Who can get secrets in the "default" namespace
+rbac-tool who-can --verb get --resource secrets --namespace default
+
+# Who can exec into pods (pods/exec subresource)
+rbac-tool who-can --verb create --resource pods --subresource exec
+
+# What can the "system:serviceaccount:kube-system:default" subject do
+rbac-tool who-can --subject system:serviceaccount:kube-system:default
+
+# Can the "system:serviceaccount:kube-system:default" subject get secrets
+rbac-tool who-can --subject system:serviceaccount:kube-system:default --verb get --resource secrets
+
+# Who can hit the non-resource URL /metrics
+rbac-tool who-can --verb get --non-resource-url /metrics
+`,
+		Hidden: false,
+		RunE: func(c *cobra.Command, args []string) error {
+			if verb == "" && subject == "" {
+				return fmt.Errorf("either --verb (with --resource or --non-resource-url) or --subject must be specified")
+			}
+
+			client, err := kube.NewClient(clusterContext)
+			if err != nil {
+				return fmt.Errorf("Failed to create kubernetes client - %v", err)
+			}
+
+			perms, err := rbac.NewPermissionsFromCluster(client)
+			if err != nil {
+				return err
+			}
+
+			policies := rbac.NewSubjectPermissions(perms)
+
+			attrs := rbac.Attrs{
+				Verb:            verb,
+				APIGroup:        apiGroup,
+				Resource:        resource,
+				Subresource:     subresource,
+				ResourceName:    resourceName,
+				NonResourceURL:  nonResourceURL,
+				IsResourceCheck: nonResourceURL == "",
+			}
+
+			filteredPolicies := []rbac.SubjectPermissions{}
+
+			for _, policy := range policies {
+				if subject != "" && policy.Subject.Name != subject {
+					continue
+				}
+
+				matchedRules := map[string][]rbacv1.PolicyRule{}
+
+				for ns, rules := range policy.Rules {
+					if namespace != "" && ns != "" && ns != namespace {
+						continue
+					}
+
+					for _, rule := range rules {
+						if verb != "" {
+							if !rbac.RuleAllows(&rule, attrs) {
+								continue
+							}
+						}
+
+						matchedRules[ns] = append(matchedRules[ns], rule)
+					}
+				}
+
+				if len(matchedRules) == 0 {
+					continue
+				}
+
+				filteredPolicies = append(filteredPolicies, rbac.SubjectPermissions{Subject: policy.Subject, Rules: matchedRules})
+			}
+
+			switch output {
+			case "table":
+				rows := [][]string{}
+
+				for _, policy := range filteredPolicies {
+					for ns, rules := range policy.Rules {
+						displayNamespace := ns
+						if displayNamespace == "" {
+							displayNamespace = "*"
+						}
+
+						for _, rule := range rules {
+							rows = append(rows, []string{
+								policy.Subject.Kind,
+								policy.Subject.Name,
+								strings.Join(rule.Verbs, ","),
+								displayNamespace,
+								strings.Join(rule.APIGroups, ","),
+								strings.Join(rule.Resources, ","),
+								strings.Join(rule.ResourceNames, ","),
+								strings.Join(rule.NonResourceURLs, ","),
+							})
+						}
+					}
+				}
+
+				sort.Slice(rows, func(i, j int) bool {
+					if strings.Compare(rows[i][0], rows[j][0]) == 0 {
+						return strings.Compare(rows[i][1], rows[j][1]) < 0
+					}
+
+					return strings.Compare(rows[i][0], rows[j][0]) < 0
+				})
+
+				table := tablewriter.NewWriter(os.Stdout)
+				table.SetHeader([]string{"TYPE", "SUBJECT", "VERBS", "NAMESPACE", "API GROUP", "RESOURCE", "NAMES", "NonResourceURI"})
+				table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+				table.SetBorder(false)
+				table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+				table.AppendBulk(rows)
+				table.Render()
+
+				return nil
+			case "yaml":
+				policies := rbac.NewSubjectPermissionsList(filteredPolicies)
+				data, err := yaml.Marshal(&policies)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			case "json":
+				policies := rbac.NewSubjectPermissionsList(filteredPolicies)
+				data, err := json.Marshal(&policies)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			default:
+				return fmt.Errorf("Unsupported output format")
+			}
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&clusterContext, "cluster-context", "", "Cluster Context .use 'kubectl config get-contexts' to list available contexts")
+	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml")
+
+	flags.StringVar(&verb, "verb", "", "Verb to check (e.g. get, list, watch, create, update, patch, delete, impersonate, *)")
+	flags.StringVar(&resource, "resource", "", "Resource to check (e.g. pods, secrets, *)")
+	flags.StringVar(&subresource, "subresource", "", "Subresource to check (e.g. exec, status)")
+	flags.StringVar(&apiGroup, "api-group", "", "API Group to check (e.g. apps, rbac.authorization.k8s.io, '' for core)")
+	flags.StringVar(&resourceName, "resource-name", "", "Specific resource instance name to check (resourceNames narrowing)")
+	flags.StringVarP(&namespace, "namespace", "n", "", "Limit the check to a specific namespace")
+	flags.StringVar(&nonResourceURL, "non-resource-url", "", "Non-resource URL to check (e.g. /metrics, /healthz)")
+	flags.StringVar(&subject, "subject", "", "Show all effective permissions for this subject name instead of answering a who-can question")
+
+	return cmd
+}
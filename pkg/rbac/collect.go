@@ -0,0 +1,137 @@
+package rbac
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/alcideio/rbac-tool/pkg/kube"
+)
+
+// SourcedRule is a single PolicyRule together with the Role/ClusterRole and
+// Binding it was aggregated from.
+type SourcedRule struct {
+	rbacv1.PolicyRule
+	Source RuleSource
+}
+
+// subjectKey identifies a subject by Kind/Namespace/Name, since a
+// ServiceAccount subject's Name is only unique within its Namespace - two
+// ServiceAccounts named "default" in different namespaces must not collapse
+// to the same key.
+func subjectKey(subject rbacv1.Subject) string {
+	return subject.Kind + "/" + subject.Namespace + "/" + subject.Name
+}
+
+// CollectSubjectRules walks every RoleBinding/ClusterRoleBinding in the
+// cluster and returns, for each subject (keyed by subjectKey) and
+// namespace ("" for cluster-scoped grants), the PolicyRules it was granted
+// together with the (RoleKind, RoleName, BindingName) they came from.
+func CollectSubjectRules(client *kube.Client) (map[string]map[string][]SourcedRule, error) {
+	roles, err := client.GetClientset().RbacV1().Roles(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	roleRules := map[string][]rbacv1.PolicyRule{}
+	for _, r := range roles.Items {
+		roleRules[r.Namespace+"/"+r.Name] = r.Rules
+	}
+
+	clusterRoles, err := client.GetClientset().RbacV1().ClusterRoles().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	clusterRoleRules := map[string][]rbacv1.PolicyRule{}
+	for _, cr := range clusterRoles.Items {
+		clusterRoleRules[cr.Name] = cr.Rules
+	}
+
+	result := map[string]map[string][]SourcedRule{}
+
+	add := func(subject rbacv1.Subject, namespace string, rules []rbacv1.PolicyRule, source RuleSource) {
+		key := subjectKey(subject)
+		if result[key] == nil {
+			result[key] = map[string][]SourcedRule{}
+		}
+
+		for _, rule := range rules {
+			result[key][namespace] = append(result[key][namespace], SourcedRule{PolicyRule: rule, Source: source})
+		}
+	}
+
+	roleBindings, err := client.GetClientset().RbacV1().RoleBindings(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, rb := range roleBindings.Items {
+		var rules []rbacv1.PolicyRule
+		switch rb.RoleRef.Kind {
+		case "Role":
+			rules = roleRules[rb.Namespace+"/"+rb.RoleRef.Name]
+		case "ClusterRole":
+			rules = clusterRoleRules[rb.RoleRef.Name]
+		}
+
+		source := RuleSource{RoleKind: rb.RoleRef.Kind, RoleName: rb.RoleRef.Name, BindingName: rb.Name}
+		for _, subject := range rb.Subjects {
+			add(subject, rb.Namespace, rules, source)
+		}
+	}
+
+	clusterRoleBindings, err := client.GetClientset().RbacV1().ClusterRoleBindings().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, crb := range clusterRoleBindings.Items {
+		rules := clusterRoleRules[crb.RoleRef.Name]
+		source := RuleSource{RoleKind: crb.RoleRef.Kind, RoleName: crb.RoleRef.Name, BindingName: crb.Name}
+		for _, subject := range crb.Subjects {
+			add(subject, "", rules, source)
+		}
+	}
+
+	return result, nil
+}
+
+// MinimizedSubjectPermissions is the --minimize projection of
+// SubjectPermissions: each namespace's rules have been deduped/merged via
+// MinimizeRules, retaining the RuleSource provenance of every rule that
+// contributed to the surviving rule.
+type MinimizedSubjectPermissions struct {
+	Subject rbacv1.Subject             `json:"subject" yaml:"subject"`
+	Rules   map[string][]MinimizedRule `json:"rules" yaml:"rules"`
+}
+
+// MinimizeSubjectPermissions minimizes every subject's rules, using sourced
+// (provenance-carrying) rules collected from the live cluster when
+// available for that subject, and falling back to minimizing the
+// (unsourced) rules already on the SubjectPermissions otherwise.
+func MinimizeSubjectPermissions(policies []SubjectPermissions, sourced map[string]map[string][]SourcedRule) []MinimizedSubjectPermissions {
+	result := make([]MinimizedSubjectPermissions, 0, len(policies))
+
+	for _, policy := range policies {
+		bySubject := sourced[subjectKey(policy.Subject)]
+
+		minimized := MinimizedSubjectPermissions{Subject: policy.Subject, Rules: map[string][]MinimizedRule{}}
+
+		for namespace, rules := range policy.Rules {
+			sourcedRules, ok := bySubject[namespace]
+			if !ok {
+				minimized.Rules[namespace] = MinimizeRules(rules, nil)
+				continue
+			}
+
+			plain := make([]rbacv1.PolicyRule, len(sourcedRules))
+			sources := make([][]RuleSource, len(sourcedRules))
+			for i, sr := range sourcedRules {
+				plain[i] = sr.PolicyRule
+				sources[i] = []RuleSource{sr.Source}
+			}
+
+			minimized.Rules[namespace] = MinimizeRules(plain, sources)
+		}
+
+		result = append(result, minimized)
+	}
+
+	return result
+}
@@ -0,0 +1,130 @@
+package rbac
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// VerbMatches returns true if the rule allows the given verb.
+// Mirrors k8s.io/kubernetes/pkg/registry/rbac/validation.VerbMatches
+func VerbMatches(rule *rbacv1.PolicyRule, verb string) bool {
+	for _, ruleVerb := range rule.Verbs {
+		if ruleVerb == rbacv1.VerbAll || ruleVerb == verb {
+			return true
+		}
+	}
+
+	return false
+}
+
+// APIGroupMatches returns true if the rule allows the given API group.
+// Mirrors k8s.io/kubernetes/pkg/registry/rbac/validation.APIGroupMatches
+func APIGroupMatches(rule *rbacv1.PolicyRule, apiGroup string) bool {
+	for _, ruleGroup := range rule.APIGroups {
+		if ruleGroup == rbacv1.APIGroupAll || ruleGroup == apiGroup {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ResourceMatches returns true if the rule allows the given resource, taking
+// into account the `resource/subresource` convention used by the API server.
+// Mirrors k8s.io/kubernetes/pkg/registry/rbac/validation.ResourceMatches
+func ResourceMatches(rule *rbacv1.PolicyRule, combinedResource, subresource string) bool {
+	for _, ruleResource := range rule.Resources {
+		// if everything is allowed, we match
+		if ruleResource == rbacv1.ResourceAll {
+			return true
+		}
+
+		// if we have an exact match, we match
+		if ruleResource == combinedResource {
+			return true
+		}
+
+		// We're in the subresource case, so check if the rule is in the format "*/subresource"
+		if len(ruleResource) == len(subresource)+2 &&
+			ruleResource[0] == '*' && ruleResource[1] == '/' &&
+			ruleResource[2:] == subresource {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ResourceNameMatches returns true if the rule allows the given resource
+// name. A rule with no ResourceNames matches all names.
+// Mirrors k8s.io/kubernetes/pkg/registry/rbac/validation.ResourceNameMatches
+func ResourceNameMatches(rule *rbacv1.PolicyRule, resourceName string) bool {
+	if len(rule.ResourceNames) == 0 {
+		return true
+	}
+
+	for _, ruleName := range rule.ResourceNames {
+		if ruleName == resourceName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NonResourceURLMatches returns true if the rule allows the given
+// non-resource URL, honoring the trailing `*` wildcard convention.
+// Mirrors k8s.io/kubernetes/pkg/registry/rbac/validation.NonResourceURLMatches
+func NonResourceURLMatches(rule *rbacv1.PolicyRule, nonResourceURL string) bool {
+	for _, ruleURL := range rule.NonResourceURLs {
+		if ruleURL == nonResourceURL {
+			return true
+		}
+
+		if len(ruleURL) > 0 && ruleURL[len(ruleURL)-1] == '*' && len(nonResourceURL) >= len(ruleURL)-1 &&
+			nonResourceURL[:len(ruleURL)-1] == ruleURL[:len(ruleURL)-1] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Attrs describes the access being checked against a PolicyRule - it mirrors
+// the subset of authorizer.Attributes that RBAC evaluation cares about.
+type Attrs struct {
+	Verb            string
+	APIGroup        string
+	Resource        string
+	Subresource     string
+	ResourceName    string
+	NonResourceURL  string
+	IsResourceCheck bool
+}
+
+// CombinedResource returns "resource/subresource" when a subresource is
+// present, or just "resource" otherwise - the form PolicyRule.Resources
+// entries are expressed in.
+func (a Attrs) CombinedResource() string {
+	if a.Subresource == "" {
+		return a.Resource
+	}
+
+	return a.Resource + "/" + a.Subresource
+}
+
+// RuleAllows returns true if the given rule grants the access described by
+// attrs. For resource checks it evaluates verb/apiGroup/resource/resourceName,
+// for non-resource checks it evaluates verb/nonResourceURL.
+func RuleAllows(rule *rbacv1.PolicyRule, attrs Attrs) bool {
+	if !VerbMatches(rule, attrs.Verb) {
+		return false
+	}
+
+	if attrs.IsResourceCheck {
+		return APIGroupMatches(rule, attrs.APIGroup) &&
+			ResourceMatches(rule, attrs.CombinedResource(), attrs.Subresource) &&
+			ResourceNameMatches(rule, attrs.ResourceName)
+	}
+
+	return NonResourceURLMatches(rule, attrs.NonResourceURL)
+}
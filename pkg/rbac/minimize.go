@@ -0,0 +1,279 @@
+package rbac
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// RuleSource identifies the Role/ClusterRole and Binding a PolicyRule was
+// aggregated from, so minimized output can still be traced back to its
+// origin.
+type RuleSource struct {
+	RoleKind    string `json:"roleKind"`
+	RoleName    string `json:"roleName"`
+	BindingName string `json:"bindingName"`
+}
+
+// MinimizedRule is a PolicyRule that survived minimization, annotated with
+// the sources it was derived from (possibly more than one, once duplicates
+// and covered rules have been merged away).
+type MinimizedRule struct {
+	rbacv1.PolicyRule `json:",inline"`
+	Sources           []RuleSource `json:"sources,omitempty"`
+}
+
+// canonicalRuleHash computes a stable FNV-1a hash over a rule's sorted
+// Verbs/APIGroups/Resources/ResourceNames/NonResourceURLs, so that two rules
+// with the same permissions in different orders hash identically.
+func canonicalRuleHash(rule rbacv1.PolicyRule) uint64 {
+	h := fnv.New64a()
+
+	write := func(field []string) {
+		sorted := append([]string{}, field...)
+		sort.Strings(sorted)
+		h.Write([]byte(strings.Join(sorted, ",")))
+		h.Write([]byte{0})
+	}
+
+	write(rule.Verbs)
+	write(rule.APIGroups)
+	write(rule.Resources)
+	write(rule.ResourceNames)
+	write(rule.NonResourceURLs)
+
+	return h.Sum64()
+}
+
+func stringSetContains(set []string, all bool, needle string) bool {
+	for _, v := range set {
+		if v == rbacv1.APIGroupAll || v == rbacv1.ResourceAll || v == rbacv1.VerbAll {
+			return true
+		}
+		if v == needle {
+			return true
+		}
+	}
+
+	return false
+}
+
+func subset(a, b []string) bool {
+	for _, v := range a {
+		if !stringSetContains(b, false, v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Covers returns true if every (verb, apiGroup, resource, resourceName,
+// nonResourceURL) tuple r1 permits is also permitted by r2, taking `*`
+// wildcard expansion into account. A rule with no ResourceNames is treated
+// as permitting all resource names.
+func Covers(r1, r2 rbacv1.PolicyRule) bool {
+	if !subset(r1.Verbs, r2.Verbs) {
+		return false
+	}
+
+	if !subset(r1.NonResourceURLs, r2.NonResourceURLs) {
+		return false
+	}
+
+	// Rules mixing resource and non-resource grants aren't comparable unless
+	// both sides are empty for the dimension being compared.
+	if len(r1.Resources) > 0 || len(r2.Resources) > 0 {
+		if !subset(r1.APIGroups, r2.APIGroups) {
+			return false
+		}
+
+		if !subset(r1.Resources, r2.Resources) {
+			return false
+		}
+
+		if len(r2.ResourceNames) > 0 {
+			if len(r1.ResourceNames) == 0 {
+				return false
+			}
+
+			if !subset(r1.ResourceNames, r2.ResourceNames) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func fieldsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortedA := append([]string{}, a...)
+	sortedB := append([]string{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// tryUnionMerge merges two rules into one if they differ in exactly one of
+// Verbs/APIGroups/Resources/ResourceNames/NonResourceURLs, by unioning that
+// one differing field. Returns the merged rule and true on success.
+func tryUnionMerge(r1, r2 rbacv1.PolicyRule) (rbacv1.PolicyRule, bool) {
+	fields := []struct {
+		get func(rbacv1.PolicyRule) []string
+		set func(*rbacv1.PolicyRule, []string)
+	}{
+		{func(r rbacv1.PolicyRule) []string { return r.Verbs }, func(r *rbacv1.PolicyRule, v []string) { r.Verbs = v }},
+		{func(r rbacv1.PolicyRule) []string { return r.APIGroups }, func(r *rbacv1.PolicyRule, v []string) { r.APIGroups = v }},
+		{func(r rbacv1.PolicyRule) []string { return r.Resources }, func(r *rbacv1.PolicyRule, v []string) { r.Resources = v }},
+		{func(r rbacv1.PolicyRule) []string { return r.ResourceNames }, func(r *rbacv1.PolicyRule, v []string) { r.ResourceNames = v }},
+		{func(r rbacv1.PolicyRule) []string { return r.NonResourceURLs }, func(r *rbacv1.PolicyRule, v []string) { r.NonResourceURLs = v }},
+	}
+
+	diffIdx := -1
+	for i, f := range fields {
+		if !fieldsEqual(f.get(r1), f.get(r2)) {
+			if diffIdx != -1 {
+				// more than one field differs - not mergeable
+				return rbacv1.PolicyRule{}, false
+			}
+			diffIdx = i
+		}
+	}
+
+	if diffIdx == -1 {
+		// identical - caller should have deduped already
+		return r1, true
+	}
+
+	merged := r1
+	union := append([]string{}, fields[diffIdx].get(r1)...)
+	for _, v := range fields[diffIdx].get(r2) {
+		if !stringSetContains(union, false, v) {
+			union = append(union, v)
+		}
+	}
+	sort.Strings(union)
+	fields[diffIdx].set(&merged, union)
+
+	return merged, true
+}
+
+// MinimizeRules collapses a set of sourced PolicyRules into an equivalent,
+// smaller set: exact duplicates (by canonical hash) are dropped, rules fully
+// covered by another surviving rule are eliminated, and rules differing in
+// exactly one field are union-merged. Provenance from every contributing
+// rule is preserved on the surviving rule.
+func MinimizeRules(rules []rbacv1.PolicyRule, sources [][]RuleSource) []MinimizedRule {
+	type entry struct {
+		rule    rbacv1.PolicyRule
+		sources []RuleSource
+	}
+
+	byHash := map[uint64]*entry{}
+	order := []uint64{}
+
+	for i, rule := range rules {
+		h := canonicalRuleHash(rule)
+		if e, ok := byHash[h]; ok {
+			if i < len(sources) {
+				e.sources = append(e.sources, sources[i]...)
+			}
+			continue
+		}
+
+		var src []RuleSource
+		if i < len(sources) {
+			src = append(src, sources[i]...)
+		}
+
+		byHash[h] = &entry{rule: rule, sources: src}
+		order = append(order, h)
+	}
+
+	entries := make([]*entry, 0, len(order))
+	for _, h := range order {
+		entries = append(entries, byHash[h])
+	}
+
+	// Eliminate rules fully covered by a different surviving rule.
+	survivors := []*entry{}
+	eliminated := []*entry{}
+	for i, e := range entries {
+		covered := false
+		for j, other := range entries {
+			if i == j {
+				continue
+			}
+
+			if Covers(e.rule, other.rule) && !Covers(other.rule, e.rule) {
+				covered = true
+				break
+			}
+		}
+
+		if !covered {
+			survivors = append(survivors, e)
+		} else {
+			eliminated = append(eliminated, e)
+		}
+	}
+
+	// Carry the provenance of every eliminated rule over to a surviving rule
+	// that covers it.
+	for _, e := range eliminated {
+		for _, s := range survivors {
+			if Covers(e.rule, s.rule) {
+				s.sources = append(s.sources, e.sources...)
+				break
+			}
+		}
+	}
+
+	// Attempt union-merging of rules differing in exactly one field, until a
+	// fixed point is reached.
+	for {
+		merged := false
+
+		for i := 0; i < len(survivors); i++ {
+			for j := i + 1; j < len(survivors); j++ {
+				if combined, ok := tryUnionMerge(survivors[i].rule, survivors[j].rule); ok {
+					survivors[i] = &entry{
+						rule:    combined,
+						sources: append(append([]RuleSource{}, survivors[i].sources...), survivors[j].sources...),
+					}
+					survivors = append(survivors[:j], survivors[j+1:]...)
+					merged = true
+					break
+				}
+			}
+
+			if merged {
+				break
+			}
+		}
+
+		if !merged {
+			break
+		}
+	}
+
+	result := make([]MinimizedRule, 0, len(survivors))
+	for _, e := range survivors {
+		result = append(result, MinimizedRule{PolicyRule: e.rule, Sources: e.sources})
+	}
+
+	return result
+}
@@ -0,0 +1,205 @@
+package rbac
+
+import (
+	"regexp"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/alcideio/rbac-tool/pkg/kube"
+)
+
+// OrphanReason describes why a subject binding was flagged.
+type OrphanReason string
+
+const (
+	// OrphanReasonMissingServiceAccount means the binding references a
+	// ServiceAccount whose namespace or SA object no longer exists.
+	OrphanReasonMissingServiceAccount OrphanReason = "MissingServiceAccount"
+	// OrphanReasonExternalIdentity means the binding references a User/Group
+	// that matched one of the configured external-identity regexes.
+	OrphanReasonExternalIdentity OrphanReason = "ExternalIdentity"
+	// OrphanReasonDanglingRoleRef means the binding's roleRef points at a
+	// Role/ClusterRole that no longer exists.
+	OrphanReasonDanglingRoleRef OrphanReason = "DanglingRoleRef"
+)
+
+// OrphanedBinding is a single flagged subject within a RoleBinding or
+// ClusterRoleBinding.
+type OrphanedBinding struct {
+	BindingKind string         `json:"bindingKind"`
+	BindingName string         `json:"bindingName"`
+	Namespace   string         `json:"namespace,omitempty"`
+	Subject     rbacv1.Subject `json:"subject"`
+	RoleRef     rbacv1.RoleRef `json:"roleRef"`
+	Reason      OrphanReason   `json:"reason"`
+	Detail      string         `json:"detail"`
+}
+
+// SubjectUID returns a stable identifier for the flagged subject, suitable
+// for passing to `cleanup-user-permissions --subject-uid`.
+func (o OrphanedBinding) SubjectUID() string {
+	ns := o.Namespace
+	if o.Subject.Namespace != "" {
+		ns = o.Subject.Namespace
+	}
+
+	return o.Subject.Kind + "/" + ns + "/" + o.Subject.Name
+}
+
+// ExternalIdentityRegexes is the default set of patterns used to recognize
+// subjects that most likely reference an external identity provider that
+// rbac-tool cannot resolve against the live cluster. By default it holds only
+// a UUID-shaped cloud IAM UID pattern; other identities (e.g. deleted LDAP
+// users) need --external-identity-regex. It intentionally does NOT include
+// SAAsUserImpersonationRegex - a User/Group literally named
+// "system:serviceaccount:<ns>:<name>" is a legitimate way to bind
+// impersonation/identity-federation permissions, not necessarily an orphan.
+var ExternalIdentityRegexes = []string{
+	`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`, // cloud IAM UID
+}
+
+// SAAsUserImpersonationRegex matches a User/Group subject named like a
+// ServiceAccount (system:serviceaccount:<ns>:<name>). This is opt-in: while
+// it's sometimes a sign of an OIDC/impersonation misconfiguration, it is also
+// a legitimate, intentional pattern, so it is not included in
+// ExternalIdentityRegexes by default.
+const SAAsUserImpersonationRegex = `^system:serviceaccount:[^:]+:[^:]+$`
+
+// ScanOrphanedSubjects walks every RoleBinding and ClusterRoleBinding in the
+// cluster and reports subjects that are orphaned, reference an external
+// identity, or dangle off a missing Role/ClusterRole.
+func ScanOrphanedSubjects(client *kube.Client, externalIdentityRegexes []string) ([]OrphanedBinding, error) {
+	patterns := make([]*regexp.Regexp, 0, len(externalIdentityRegexes))
+	for _, p := range externalIdentityRegexes {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, re)
+	}
+
+	namespaces, err := client.GetClientset().CoreV1().Namespaces().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	existingNamespaces := map[string]bool{}
+	for _, ns := range namespaces.Items {
+		existingNamespaces[ns.Name] = true
+	}
+
+	serviceAccounts, err := client.GetClientset().CoreV1().ServiceAccounts(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	existingServiceAccounts := map[string]bool{}
+	for _, sa := range serviceAccounts.Items {
+		existingServiceAccounts[sa.Namespace+"/"+sa.Name] = true
+	}
+
+	roles, err := client.GetClientset().RbacV1().Roles(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	existingRoles := map[string]bool{}
+	for _, r := range roles.Items {
+		existingRoles[r.Namespace+"/"+r.Name] = true
+	}
+
+	clusterRoles, err := client.GetClientset().RbacV1().ClusterRoles().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	existingClusterRoles := map[string]bool{}
+	for _, cr := range clusterRoles.Items {
+		existingClusterRoles[cr.Name] = true
+	}
+
+	findings := []OrphanedBinding{}
+
+	checkRoleRef := func(kind, name, namespace string, roleRef rbacv1.RoleRef) *OrphanedBinding {
+		exists := false
+		switch roleRef.Kind {
+		case "ClusterRole":
+			exists = existingClusterRoles[roleRef.Name]
+		case "Role":
+			exists = existingRoles[namespace+"/"+roleRef.Name]
+		}
+
+		if exists {
+			return nil
+		}
+
+		return &OrphanedBinding{
+			BindingKind: kind,
+			BindingName: name,
+			Namespace:   namespace,
+			RoleRef:     roleRef,
+			Reason:      OrphanReasonDanglingRoleRef,
+			Detail:      "roleRef " + roleRef.Kind + "/" + roleRef.Name + " does not exist",
+		}
+	}
+
+	checkSubject := func(kind, name, namespace string, roleRef rbacv1.RoleRef, subject rbacv1.Subject) *OrphanedBinding {
+		switch subject.Kind {
+		case rbacv1.ServiceAccountKind:
+			ns := subject.Namespace
+			if ns == "" {
+				ns = namespace
+			}
+
+			if !existingNamespaces[ns] {
+				return &OrphanedBinding{BindingKind: kind, BindingName: name, Namespace: namespace, Subject: subject, RoleRef: roleRef,
+					Reason: OrphanReasonMissingServiceAccount, Detail: "namespace " + ns + " does not exist"}
+			}
+
+			if !existingServiceAccounts[ns+"/"+subject.Name] {
+				return &OrphanedBinding{BindingKind: kind, BindingName: name, Namespace: namespace, Subject: subject, RoleRef: roleRef,
+					Reason: OrphanReasonMissingServiceAccount, Detail: "service account " + ns + "/" + subject.Name + " does not exist"}
+			}
+		case rbacv1.UserKind, rbacv1.GroupKind:
+			for _, re := range patterns {
+				if re.MatchString(subject.Name) {
+					return &OrphanedBinding{BindingKind: kind, BindingName: name, Namespace: namespace, Subject: subject, RoleRef: roleRef,
+						Reason: OrphanReasonExternalIdentity, Detail: "subject name matches external-identity pattern " + re.String()}
+				}
+			}
+		}
+
+		return nil
+	}
+
+	roleBindings, err := client.GetClientset().RbacV1().RoleBindings(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, rb := range roleBindings.Items {
+		if f := checkRoleRef("RoleBinding", rb.Name, rb.Namespace, rb.RoleRef); f != nil {
+			findings = append(findings, *f)
+		}
+
+		for _, subject := range rb.Subjects {
+			if f := checkSubject("RoleBinding", rb.Name, rb.Namespace, rb.RoleRef, subject); f != nil {
+				findings = append(findings, *f)
+			}
+		}
+	}
+
+	clusterRoleBindings, err := client.GetClientset().RbacV1().ClusterRoleBindings().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, crb := range clusterRoleBindings.Items {
+		if f := checkRoleRef("ClusterRoleBinding", crb.Name, "", crb.RoleRef); f != nil {
+			findings = append(findings, *f)
+		}
+
+		for _, subject := range crb.Subjects {
+			if f := checkSubject("ClusterRoleBinding", crb.Name, "", crb.RoleRef, subject); f != nil {
+				findings = append(findings, *f)
+			}
+		}
+	}
+
+	return findings, nil
+}
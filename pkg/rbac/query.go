@@ -0,0 +1,207 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/jmespath/go-jmespath"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// FilterByJMESPath evaluates a JMESPath expression against the JSON
+// representation of a SubjectPermissionsList and returns the raw result,
+// ready to be marshaled as json/yaml. Typical expressions operate on the
+// top-level list, e.g.:
+//
+//	[?contains(allowedTo[].verbs[], 'get')] | [?contains(allowedTo[].apiGroups[], '')]
+func FilterByJMESPath(list SubjectPermissionsList, query string) (interface{}, error) {
+	raw, err := json.Marshal(&list)
+	if err != nil {
+		return nil, fmt.Errorf("Processing error - %v", err)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("Processing error - %v", err)
+	}
+
+	result, err := jmespath.Search(query, data)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid --query expression - %v", err)
+	}
+
+	return result, nil
+}
+
+func ruleToCELMap(rule rbacv1.PolicyRule) map[string]interface{} {
+	return map[string]interface{}{
+		"verbs":           toInterfaceSlice(rule.Verbs),
+		"apiGroups":       toInterfaceSlice(rule.APIGroups),
+		"resources":       toInterfaceSlice(rule.Resources),
+		"resourceNames":   toInterfaceSlice(rule.ResourceNames),
+		"nonResourceURLs": toInterfaceSlice(rule.NonResourceURLs),
+	}
+}
+
+func toInterfaceSlice(in []string) []interface{} {
+	out := make([]interface{}, len(in))
+	for i, v := range in {
+		out[i] = v
+	}
+
+	return out
+}
+
+// celEnv builds the CEL environment shared by FilterByCEL: a boolean
+// expression evaluated once per subject, with `subject` (map with name/kind)
+// and `rules` (list of rule maps: verbs/apiGroups/resources/resourceNames/
+// nonResourceURLs) in scope, plus verbMatches/resourceMatches/apiGroupMatches
+// helpers mirroring the upstream RBAC evaluator.
+func celEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("subject", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("rules", cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+		cel.Function("verbMatches",
+			cel.Overload("verbMatches_map_string",
+				[]*cel.Type{cel.MapType(cel.StringType, cel.DynType), cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(celVerbMatches)),
+		),
+		cel.Function("apiGroupMatches",
+			cel.Overload("apiGroupMatches_map_string",
+				[]*cel.Type{cel.MapType(cel.StringType, cel.DynType), cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(celAPIGroupMatches)),
+		),
+		cel.Function("resourceMatches",
+			cel.Overload("resourceMatches_map_string",
+				[]*cel.Type{cel.MapType(cel.StringType, cel.DynType), cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(celResourceMatches)),
+		),
+	)
+}
+
+func celStringListField(m ref.Val, field string) []string {
+	mapper, ok := m.(traits.Mapper)
+	if !ok {
+		return nil
+	}
+
+	v, found := mapper.Find(types.String(field))
+	if !found {
+		return nil
+	}
+
+	lister, ok := v.(traits.Lister)
+	if !ok {
+		return nil
+	}
+
+	out := []string{}
+	it := lister.Iterator()
+	for it.HasNext() == types.True {
+		out = append(out, fmt.Sprintf("%v", it.Next().Value()))
+	}
+
+	return out
+}
+
+func celVerbMatches(ruleVal, verbVal ref.Val) ref.Val {
+	verb, ok := verbVal.Value().(string)
+	if !ok {
+		return types.False
+	}
+
+	for _, v := range celStringListField(ruleVal, "verbs") {
+		if v == rbacv1.VerbAll || v == verb {
+			return types.True
+		}
+	}
+
+	return types.False
+}
+
+func celAPIGroupMatches(ruleVal, groupVal ref.Val) ref.Val {
+	group, ok := groupVal.Value().(string)
+	if !ok {
+		return types.False
+	}
+
+	for _, g := range celStringListField(ruleVal, "apiGroups") {
+		if g == rbacv1.APIGroupAll || g == group {
+			return types.True
+		}
+	}
+
+	return types.False
+}
+
+func celResourceMatches(ruleVal, resourceVal ref.Val) ref.Val {
+	resource, ok := resourceVal.Value().(string)
+	if !ok {
+		return types.False
+	}
+
+	subresource := ""
+	if idx := strings.Index(resource, "/"); idx >= 0 {
+		subresource = resource[idx+1:]
+	}
+
+	rule := rbacv1.PolicyRule{Resources: celStringListField(ruleVal, "resources")}
+	if ResourceMatches(&rule, resource, subresource) {
+		return types.True
+	}
+
+	return types.False
+}
+
+// FilterByCEL evaluates a CEL boolean expression once per subject in
+// policies, with `subject` and `rules` bound in the activation, and keeps
+// only the subjects for which the expression evaluates to true.
+func FilterByCEL(policies []SubjectPermissions, expr string) ([]SubjectPermissions, error) {
+	env, err := celEnv()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to build CEL environment - %v", err)
+	}
+
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("Invalid --cel expression - %v", issues.Err())
+	}
+
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid --cel expression - %v", err)
+	}
+
+	filtered := []SubjectPermissions{}
+	for _, policy := range policies {
+		rules := []interface{}{}
+		for _, nsRules := range policy.Rules {
+			for _, rule := range nsRules {
+				rules = append(rules, ruleToCELMap(rule))
+			}
+		}
+
+		out, _, err := prg.Eval(map[string]interface{}{
+			"subject": map[string]interface{}{
+				"name": policy.Subject.Name,
+				"kind": policy.Subject.Kind,
+			},
+			"rules": rules,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to evaluate --cel expression for subject %v - %v", policy.Subject.Name, err)
+		}
+
+		match, ok := out.Value().(bool)
+		if ok && match {
+			filtered = append(filtered, policy)
+		}
+	}
+
+	return filtered, nil
+}
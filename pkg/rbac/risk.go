@@ -0,0 +1,337 @@
+package rbac
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Severity is the qualitative level a RiskRule carries alongside its numeric
+// Score. It is set per rule and is not derived from the Score.
+type Severity string
+
+const (
+	SeverityLow      Severity = "Low"
+	SeverityMedium   Severity = "Medium"
+	SeverityHigh     Severity = "High"
+	SeverityCritical Severity = "Critical"
+)
+
+// severityRank orders severities from least to most dangerous, so
+// `--min-severity` can be compared with a simple integer rank.
+var severityRank = map[Severity]int{
+	SeverityLow:      0,
+	SeverityMedium:   1,
+	SeverityHigh:     2,
+	SeverityCritical: 3,
+}
+
+// rankOf returns a severity's rank, or -1 for the empty/unknown severity
+// (e.g. a subject with no matching RiskFindings), so it ranks below SeverityLow.
+func rankOf(s Severity) int {
+	rank, ok := severityRank[s]
+	if !ok {
+		return -1
+	}
+
+	return rank
+}
+
+// RuleAtLeastAsSevereAs returns true if a is the same severity as, or more
+// dangerous than, b. Unknown severities rank below SeverityLow.
+func RuleAtLeastAsSevereAs(a, b Severity) bool {
+	return rankOf(a) >= rankOf(b)
+}
+
+// ParseSeverity parses a --min-severity flag value case-insensitively
+// against {Low,Medium,High,Critical}, returning an error for anything else
+// so an unrecognized value can't silently rank below every severity and
+// match every subject.
+func ParseSeverity(s string) (Severity, error) {
+	for severity := range severityRank {
+		if strings.EqualFold(string(severity), s) {
+			return severity, nil
+		}
+	}
+
+	return "", fmt.Errorf("Unknown severity %q - expected one of Low, Medium, High, Critical", s)
+}
+
+// RiskRule describes a well-known dangerous permission pattern: a subject
+// with a rule that grants any verb listed here on any resource listed here
+// (in any of the listed apiGroups) contributes Score points at severity
+// Severity, with Rationale explaining why.
+type RiskRule struct {
+	Name      string   `json:"name" yaml:"name"`
+	Verbs     []string `json:"verbs" yaml:"verbs"`
+	APIGroups []string `json:"apiGroups" yaml:"apiGroups"`
+	Resources []string `json:"resources" yaml:"resources"`
+	Score     int      `json:"score" yaml:"score"`
+	Severity  Severity `json:"severity" yaml:"severity"`
+	Rationale string   `json:"rationale" yaml:"rationale"`
+}
+
+// matches returns true if rule grants any verb in r.Verbs on any resource in
+// r.Resources within any API group in r.APIGroups - the same any-of-any-of
+// semantics kubectl auth can-i uses, not a requirement that rule cover every
+// combination at once. Resources may use the "resource/subresource" form
+// (e.g. "pods/exec"); an empty list for any dimension matches everything. A
+// rule restricted to specific ResourceNames never matches, since RiskRules
+// describe access to a resource type as a whole (e.g. "can read all
+// Secrets"), not to a single named object.
+func (r RiskRule) matches(rule *rbacv1.PolicyRule) bool {
+	if len(rule.ResourceNames) > 0 {
+		return false
+	}
+
+	if len(r.Verbs) > 0 {
+		matched := false
+		for _, verb := range r.Verbs {
+			if VerbMatches(rule, verb) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(r.APIGroups) > 0 {
+		matched := false
+		for _, group := range r.APIGroups {
+			if APIGroupMatches(rule, group) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(r.Resources) > 0 {
+		matched := false
+		for _, resource := range r.Resources {
+			combined, subresource := resource, ""
+			if idx := strings.Index(resource, "/"); idx >= 0 {
+				subresource = resource[idx+1:]
+			}
+			if ResourceMatches(rule, combined, subresource) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RiskPolicy is an ordered set of RiskRules, typically loaded from a YAML
+// file via --risk-policy or from DefaultRiskPolicy().
+type RiskPolicy struct {
+	Rules []RiskRule `json:"rules" yaml:"rules"`
+}
+
+// LoadRiskPolicy reads and parses a RiskPolicy from a YAML file.
+func LoadRiskPolicy(path string) (RiskPolicy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return RiskPolicy{}, fmt.Errorf("Failed to read risk policy %v - %v", path, err)
+	}
+
+	var policy RiskPolicy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return RiskPolicy{}, fmt.Errorf("Failed to parse risk policy %v - %v", path, err)
+	}
+
+	return policy, nil
+}
+
+// defaultRiskPolicyYAML is the built-in set of dangerous permission
+// patterns, used whenever --risk-policy is not specified.
+const defaultRiskPolicyYAML = `
+rules:
+- name: cluster-admin-wildcard
+  verbs: ["*"]
+  apiGroups: ["*"]
+  resources: ["*"]
+  score: 100
+  severity: Critical
+  rationale: Grants every verb on every resource in every API group (cluster-admin equivalent)
+- name: read-secrets
+  verbs: ["get", "list", "watch"]
+  apiGroups: [""]
+  resources: ["secrets"]
+  score: 40
+  severity: High
+  rationale: Can read all Secrets, including other workloads' credentials
+- name: exec-into-pods
+  verbs: ["create"]
+  apiGroups: [""]
+  resources: ["pods/exec"]
+  score: 60
+  severity: Critical
+  rationale: Can exec into any Pod, equivalent to root on the node for that workload
+- name: attach-to-pods
+  verbs: ["create"]
+  apiGroups: [""]
+  resources: ["pods/attach"]
+  score: 60
+  severity: Critical
+  rationale: Can attach to any Pod's running process
+- name: impersonate-identities
+  verbs: ["impersonate"]
+  apiGroups: [""]
+  resources: ["users", "groups", "serviceaccounts"]
+  score: 80
+  severity: Critical
+  rationale: Can impersonate any user, group, or service account, bypassing RBAC entirely
+- name: escalate-roles
+  verbs: ["escalate"]
+  apiGroups: ["rbac.authorization.k8s.io"]
+  resources: ["roles", "clusterroles"]
+  score: 90
+  severity: Critical
+  rationale: Can grant itself any permission via the escalate verb
+- name: bind-roles
+  verbs: ["bind"]
+  apiGroups: ["rbac.authorization.k8s.io"]
+  resources: ["roles", "clusterroles"]
+  score: 70
+  severity: High
+  rationale: Can bind any Role/ClusterRole to a subject of its choosing
+- name: create-tokenrequests
+  verbs: ["create"]
+  apiGroups: ["authentication.k8s.io"]
+  resources: ["tokenrequests"]
+  score: 50
+  severity: High
+  rationale: Can mint tokens for any service account
+- name: create-serviceaccount-tokens
+  verbs: ["create"]
+  apiGroups: [""]
+  resources: ["serviceaccounts/token"]
+  score: 50
+  severity: High
+  rationale: Can mint tokens for any service account via the token subresource
+- name: modify-admission-webhooks
+  verbs: ["create", "update", "patch", "delete"]
+  apiGroups: ["admissionregistration.k8s.io"]
+  resources: ["validatingwebhookconfigurations"]
+  score: 70
+  severity: High
+  rationale: Can tamper with validating admission control for the whole cluster
+- name: modify-mutating-webhooks
+  verbs: ["create", "update", "patch", "delete"]
+  apiGroups: ["admissionregistration.k8s.io"]
+  resources: ["mutatingwebhookconfigurations"]
+  score: 80
+  severity: Critical
+  rationale: Can tamper with mutating admission control for the whole cluster
+- name: node-proxy
+  verbs: ["get", "create"]
+  apiGroups: [""]
+  resources: ["nodes/proxy"]
+  score: 60
+  severity: High
+  rationale: Can proxy to kubelet APIs on any node
+- name: approve-csrs
+  verbs: ["update"]
+  apiGroups: ["certificates.k8s.io"]
+  resources: ["certificatesigningrequests/approval"]
+  score: 60
+  severity: High
+  rationale: Can approve arbitrary certificate signing requests, minting trusted client certs
+`
+
+// DefaultRiskPolicy returns the built-in RiskPolicy described in
+// defaultRiskPolicyYAML.
+func DefaultRiskPolicy() RiskPolicy {
+	var policy RiskPolicy
+	// The embedded policy is a package-level constant; a parse error here
+	// would be a programmer error.
+	if err := yaml.Unmarshal([]byte(defaultRiskPolicyYAML), &policy); err != nil {
+		panic(fmt.Sprintf("invalid embedded default risk policy: %v", err))
+	}
+
+	return policy
+}
+
+// RiskFinding is a single RiskRule that matched a subject's rules.
+type RiskFinding struct {
+	Rule      string   `json:"rule" yaml:"rule"`
+	Score     int      `json:"score" yaml:"score"`
+	Severity  Severity `json:"severity" yaml:"severity"`
+	Rationale string   `json:"rationale" yaml:"rationale"`
+}
+
+// SubjectRiskScore is the aggregated risk for a single subject.
+type SubjectRiskScore struct {
+	Subject  rbacv1.Subject `json:"subject" yaml:"subject"`
+	Score    int            `json:"score" yaml:"score"`
+	Severity Severity       `json:"severity" yaml:"severity"`
+	Findings []RiskFinding  `json:"findings" yaml:"findings"`
+}
+
+// ScoreSubject evaluates every rule in policy against the subject's
+// permissions and returns the aggregated score, worst-case severity, and the
+// list of RiskRules that matched.
+func ScoreSubject(policy RiskPolicy, perm SubjectPermissions) SubjectRiskScore {
+	result := SubjectRiskScore{Subject: perm.Subject}
+
+	for _, riskRule := range policy.Rules {
+		matched := false
+
+		for _, rules := range perm.Rules {
+			for i := range rules {
+				if riskRule.matches(&rules[i]) {
+					matched = true
+					break
+				}
+			}
+			if matched {
+				break
+			}
+		}
+
+		if !matched {
+			continue
+		}
+
+		result.Score += riskRule.Score
+		if rankOf(riskRule.Severity) > rankOf(result.Severity) {
+			result.Severity = riskRule.Severity
+		}
+
+		result.Findings = append(result.Findings, RiskFinding{
+			Rule:      riskRule.Name,
+			Score:     riskRule.Score,
+			Severity:  riskRule.Severity,
+			Rationale: riskRule.Rationale,
+		})
+	}
+
+	sort.Slice(result.Findings, func(i, j int) bool {
+		return result.Findings[i].Score > result.Findings[j].Score
+	})
+
+	return result
+}
+
+// ScoreSubjects runs ScoreSubject over every subject in perms.
+func ScoreSubjects(policy RiskPolicy, perms []SubjectPermissions) []SubjectRiskScore {
+	scores := make([]SubjectRiskScore, 0, len(perms))
+	for _, perm := range perms {
+		scores = append(scores, ScoreSubject(policy, perm))
+	}
+
+	return scores
+}